@@ -4,7 +4,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/christerso/shared-state-manager/managers"
+	"github.com/christerso/shared-state-manager"
 )
 
 func main() {
@@ -0,0 +1,261 @@
+package managers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	walFileName      = "wal.log"
+	snapshotFileName = "snapshot.json"
+)
+
+// FileStoreConfig configures a FileStore.
+type FileStoreConfig struct {
+	// Dir is the directory holding the WAL file and snapshot file. Created
+	// if it doesn't already exist.
+	Dir string
+	// FsyncEvery is how often buffered WAL writes are flushed and synced to
+	// disk. Zero or negative fsyncs after every Append (safest, slowest);
+	// a positive value instead fsyncs on that cadence from a background
+	// goroutine, trading a small durability window for write throughput.
+	FsyncEvery time.Duration
+	// SnapshotInterval, if positive, makes NewSharedStateManagerWithStore
+	// start a background goroutine that periodically compacts the WAL into
+	// a fresh snapshot. Zero disables automatic compaction; callers can
+	// still call SharedStateManager.Compact manually.
+	SnapshotInterval time.Duration
+	// OnError is called for failures that happen off the hot write path
+	// (background fsync, background compaction, or a failed Append). Nil
+	// discards them.
+	OnError func(error)
+}
+
+// FileStore is a Store backed by a write-ahead log file plus periodic JSON
+// snapshots, giving a SharedStateManager crash-tolerant persistence instead
+// of purely in-memory state.
+//
+// Because values round-trip through encoding/json, a recovered value's
+// concrete Go type is whatever json.Unmarshal produces into an
+// interface{} — not necessarily the type it was Set with. Notably, any
+// numeric value comes back as float64, so e.g. ssm.Set("n", 42) followed by
+// a restart yields Get("n") == float64(42), and managers/typed's Get[int] on
+// the same key will report not-found after recovery. Callers that need
+// exact type preservation across a restart should either not rely on
+// interface{} numerics for keys stored in a FileStore, or layer a
+// type-tagged encoding on top (FileStore has no such envelope today).
+type FileStore struct {
+	config FileStoreConfig
+
+	mu        sync.Mutex
+	walFile   *os.File
+	walWriter *bufio.Writer
+	encoder   *json.Encoder
+
+	fsyncStop chan struct{}
+}
+
+// NewFileStore opens (creating if necessary) the WAL file under config.Dir.
+func NewFileStore(config FileStoreConfig) (*FileStore, error) {
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("managers: creating store dir: %w", err)
+	}
+
+	walFile, err := os.OpenFile(filepath.Join(config.Dir, walFileName), os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("managers: opening WAL: %w", err)
+	}
+
+	writer := bufio.NewWriter(walFile)
+	fs := &FileStore{
+		config:    config,
+		walFile:   walFile,
+		walWriter: writer,
+		encoder:   json.NewEncoder(writer),
+	}
+
+	if config.FsyncEvery > 0 {
+		fs.fsyncStop = make(chan struct{})
+		go fs.periodicFsync()
+	}
+
+	return fs, nil
+}
+
+func (fs *FileStore) periodicFsync() {
+	ticker := time.NewTicker(fs.config.FsyncEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			fs.mu.Lock()
+			err := fs.flushLocked()
+			fs.mu.Unlock()
+			if err != nil {
+				fs.reportError(fmt.Errorf("managers: periodic fsync: %w", err))
+			}
+		case <-fs.fsyncStop:
+			return
+		}
+	}
+}
+
+// flushLocked flushes buffered writes and fsyncs the WAL. Callers must hold
+// fs.mu.
+func (fs *FileStore) flushLocked() error {
+	if err := fs.walWriter.Flush(); err != nil {
+		return err
+	}
+	return fs.walFile.Sync()
+}
+
+func (fs *FileStore) reportError(err error) {
+	if fs.config.OnError != nil {
+		fs.config.OnError(err)
+	}
+}
+
+// Append writes rec to the WAL. With FsyncEvery unset, Append fsyncs before
+// returning so the write is durable by the time it returns; otherwise a
+// background goroutine fsyncs on that cadence instead, and Append only
+// flushes the buffer.
+func (fs *FileStore) Append(rec Record) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.encoder.Encode(rec); err != nil {
+		fs.reportError(fmt.Errorf("managers: appending WAL record: %w", err))
+		return
+	}
+
+	var err error
+	if fs.config.FsyncEvery <= 0 {
+		err = fs.flushLocked()
+	} else {
+		err = fs.walWriter.Flush()
+	}
+	if err != nil {
+		fs.reportError(fmt.Errorf("managers: flushing WAL: %w", err))
+	}
+}
+
+// LoadSnapshot reads the most recent snapshot file, if any.
+func (fs *FileStore) LoadSnapshot() (map[string]StoredValue, bool, error) {
+	data, err := os.ReadFile(filepath.Join(fs.config.Dir, snapshotFileName))
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	var state map[string]StoredValue
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, false, fmt.Errorf("managers: decoding snapshot: %w", err)
+	}
+	return state, true, nil
+}
+
+// LoadTail reads every WAL record appended since the last snapshot.
+func (fs *FileStore) LoadTail() ([]Record, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.walWriter.Flush(); err != nil {
+		return nil, fmt.Errorf("managers: flushing WAL: %w", err)
+	}
+	if _, err := fs.walFile.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("managers: seeking WAL: %w", err)
+	}
+	defer fs.walFile.Seek(0, io.SeekEnd)
+
+	var records []Record
+	decoder := json.NewDecoder(fs.walFile)
+	for decoder.More() {
+		var rec Record
+		if err := decoder.Decode(&rec); err != nil {
+			return nil, fmt.Errorf("managers: decoding WAL record: %w", err)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// SaveSnapshot atomically installs state as the new snapshot and truncates
+// the WAL, since every record in it is now reflected in the snapshot.
+func (fs *FileStore) SaveSnapshot(state map[string]StoredValue) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("managers: encoding snapshot: %w", err)
+	}
+
+	snapshotPath := filepath.Join(fs.config.Dir, snapshotFileName)
+	tmpPath := snapshotPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("managers: writing snapshot: %w", err)
+	}
+	if err := os.Rename(tmpPath, snapshotPath); err != nil {
+		return fmt.Errorf("managers: installing snapshot: %w", err)
+	}
+
+	if err := fs.walFile.Truncate(0); err != nil {
+		return fmt.Errorf("managers: truncating WAL: %w", err)
+	}
+	if _, err := fs.walFile.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("managers: seeking WAL: %w", err)
+	}
+	fs.walWriter.Reset(fs.walFile)
+
+	return nil
+}
+
+// Close flushes and closes the WAL file, stopping the background fsync
+// goroutine if FsyncEvery started one.
+func (fs *FileStore) Close() error {
+	if fs.fsyncStop != nil {
+		close(fs.fsyncStop)
+	}
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.flushLocked(); err != nil {
+		return err
+	}
+	return fs.walFile.Close()
+}
+
+// StartCompactor starts a background goroutine that calls compact every
+// interval. The returned stop function ends the goroutine.
+//
+// compact is expected to be SharedStateManager.Compact (or equivalent),
+// which captures the current state and saves it as a new snapshot
+// atomically with respect to concurrent writes. Building the snapshot here
+// instead and merely saving it through compact would reopen exactly that
+// race: a write landing between the capture and SaveSnapshot's WAL truncate
+// would end up in neither.
+func (fs *FileStore) StartCompactor(compact func() error, interval time.Duration) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := compact(); err != nil {
+					fs.reportError(fmt.Errorf("managers: compacting: %w", err))
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,158 @@
+// Package client is the remote counterpart to managers.SharedStateManager:
+// it mirrors the same method names and signatures where the transport
+// allows, so code written against the in-process manager can switch to a
+// managers/server HTTPServer with minimal changes. It speaks the HTTP+SSE
+// shim rather than gRPC, so it has no protoc-generated dependency.
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/christerso/shared-state-manager"
+)
+
+// Client talks to a managers/server HTTPServer over HTTP and SSE.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewClient returns a Client pointed at baseURL (e.g. "http://localhost:8080"),
+// using http.DefaultClient.
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// Set stores value for key on the remote manager.
+func (c *Client) Set(key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(struct {
+		Key   string          `json:"key"`
+		Value json.RawMessage `json:"value"`
+	}{key, data})
+	if err != nil {
+		return err
+	}
+	return c.post("/v1/set", body)
+}
+
+// SetWithTimeout stores value for key with an expiration on the remote manager.
+func (c *Client) SetWithTimeout(key string, value interface{}, duration time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(struct {
+		Key        string          `json:"key"`
+		Value      json.RawMessage `json:"value"`
+		DurationMs int64           `json:"duration_ms"`
+	}{key, data, duration.Milliseconds()})
+	if err != nil {
+		return err
+	}
+	return c.post("/v1/set_with_timeout", body)
+}
+
+// Delete removes key on the remote manager.
+func (c *Client) Delete(key string) error {
+	body, err := json.Marshal(struct {
+		Key string `json:"key"`
+	}{key})
+	if err != nil {
+		return err
+	}
+	return c.post("/v1/delete", body)
+}
+
+// Get retrieves the value stored under key from the remote manager.
+func (c *Client) Get(key string) (interface{}, bool, error) {
+	resp, err := c.httpClient().Get(c.BaseURL + "/v1/get?" + url.Values{"key": {key}}.Encode())
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("client: get %s: unexpected status %d", key, resp.StatusCode)
+	}
+	var value interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&value); err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (c *Client) post(path string, body []byte) error {
+	resp, err := c.httpClient().Post(c.BaseURL+path, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("client: %s: unexpected status %d", path, resp.StatusCode)
+	}
+	return nil
+}
+
+// Subscribe opens a server-streaming subscription to key, delivering values
+// and managers.ExpirationNotification on the returned channel until the stop
+// function is called or the connection breaks.
+func (c *Client) Subscribe(key string, conditional bool) (<-chan interface{}, func(), error) {
+	query := url.Values{"key": {key}, "conditional": {fmt.Sprintf("%t", conditional)}}
+	reqURL := c.BaseURL + "/v1/subscribe?" + query.Encode()
+	req, err := http.NewRequest(http.MethodGet, reqURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	out := make(chan interface{})
+	go func() {
+		defer close(out)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		var event string
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data := strings.TrimPrefix(line, "data: ")
+				if event == "expired" {
+					out <- managers.ExpirationNotification{Key: key}
+					continue
+				}
+				var value interface{}
+				if err := json.Unmarshal([]byte(data), &value); err == nil {
+					out <- value
+				}
+			}
+		}
+	}()
+
+	stop := func() { resp.Body.Close() }
+	return out, stop, nil
+}
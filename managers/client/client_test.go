@@ -0,0 +1,37 @@
+package client
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/christerso/shared-state-manager"
+	"github.com/christerso/shared-state-manager/managers/server"
+)
+
+// TestClientEscapesSpecialCharactersInKeys guards against a regression where
+// Get and Subscribe built query strings with fmt.Sprintf instead of
+// net/url, so a key containing '&', '#', '%', or spaces would corrupt the
+// query string or silently target the wrong key.
+func TestClientEscapesSpecialCharactersInKeys(t *testing.T) {
+	ssm := managers.NewSharedStateManager()
+	srv := httptest.NewServer(server.NewHTTPServer(ssm).Handler())
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	const key = "weird key?with=special&chars#100%"
+
+	if err := c.Set(key, "value"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	value, exists, err := c.Get(key)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if !exists {
+		t.Fatalf("expected key %q to exist after Set", key)
+	}
+	if value != "value" {
+		t.Fatalf("expected %q, got %q", "value", value)
+	}
+}
@@ -0,0 +1,239 @@
+// Package cluster extends managers.SharedStateManager into an optional
+// clustered mode: multiple processes replicate the same key-value state
+// through a Raft log, so writes are strongly consistent and survive node
+// failure. Reads are served locally from the in-memory state, just like the
+// unclustered manager.
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/raft"
+
+	"github.com/christerso/shared-state-manager"
+)
+
+// ErrNotLeader is returned by writes issued against a follower. Callers
+// should retry against Leader() — this package has no built-in forwarding,
+// since doing so correctly needs a way to map a Raft server address to a
+// reachable API address (e.g. a managers/client endpoint), which is
+// deployment-specific and out of scope here.
+var ErrNotLeader = errors.New("cluster: this node is not the Raft leader")
+
+// ClusterConfig configures a clustered SharedStateManager.
+type ClusterConfig struct {
+	// NodeID uniquely identifies this node within the cluster.
+	NodeID string
+	// RaftDir is the directory Raft uses for its log store, stable store,
+	// and snapshots.
+	RaftDir string
+	// BindAddr is the local address Raft's transport listens on.
+	BindAddr string
+	// Bootstrap starts a brand-new single-node cluster that later nodes can
+	// Join. Exactly one node in a fresh cluster should set this.
+	Bootstrap bool
+	// SnapshotInterval and SnapshotThreshold tune how often Raft compacts its
+	// log into a stateMap snapshot. Zero values fall back to Raft's defaults.
+	SnapshotInterval  time.Duration
+	SnapshotThreshold uint64
+}
+
+// ClusteredSharedStateManager wraps a managers.SharedStateManager so reads
+// (Get, GetString, GetStruct, Subscribe) behave identically to the
+// unclustered manager, while writes (Set, SetWithTimeout, Delete) go through
+// the Raft log before being applied to local state.
+type ClusteredSharedStateManager struct {
+	*managers.SharedStateManager
+
+	raft   *raft.Raft
+	fsm    *fsm
+	config ClusterConfig
+}
+
+// leaderNotifyBuffer is the buffer size of the channel raft.Config.NotifyCh
+// sends leadership transitions on. raft.Raft blocks sending to this channel
+// (except while shutting down) if it isn't drained, so watchLeadership must
+// always be ready to receive; 1 is enough buffer for that to hold even if a
+// transition arrives while watchLeadership is still handling the last one.
+const leaderNotifyBuffer = 1
+
+// NewClusteredSharedStateManager starts (or rejoins) a Raft-backed node and
+// returns a manager whose writes are replicated to the rest of the cluster.
+func NewClusteredSharedStateManager(config ClusterConfig) (*ClusteredSharedStateManager, error) {
+	transport, err := raft.NewTCPTransport(config.BindAddr, nil, 3, 10*time.Second, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cluster: creating transport: %w", err)
+	}
+	return newClusteredSharedStateManager(config, transport)
+}
+
+// newClusteredSharedStateManager does the transport-agnostic work of
+// NewClusteredSharedStateManager. It's split out so tests can supply
+// raft.NewInmemTransport instead of a real TCP listener.
+func newClusteredSharedStateManager(config ClusterConfig, transport raft.Transport) (*ClusteredSharedStateManager, error) {
+	ssm := managers.NewSharedStateManager()
+	f := &fsm{ssm: ssm}
+
+	raftConfig := raft.DefaultConfig()
+	raftConfig.LocalID = raft.ServerID(config.NodeID)
+	if config.SnapshotInterval > 0 {
+		raftConfig.SnapshotInterval = config.SnapshotInterval
+	}
+	if config.SnapshotThreshold > 0 {
+		raftConfig.SnapshotThreshold = config.SnapshotThreshold
+	}
+	leaderCh := make(chan bool, leaderNotifyBuffer)
+	raftConfig.NotifyCh = leaderCh
+
+	snapshots, err := raft.NewFileSnapshotStore(config.RaftDir, 2, nil)
+	if err != nil {
+		return nil, fmt.Errorf("cluster: creating snapshot store: %w", err)
+	}
+
+	logStore, stableStore, err := newStores(config.RaftDir)
+	if err != nil {
+		return nil, fmt.Errorf("cluster: creating log/stable store: %w", err)
+	}
+
+	r, err := raft.NewRaft(raftConfig, f, logStore, stableStore, snapshots, transport)
+	if err != nil {
+		return nil, fmt.Errorf("cluster: starting raft: %w", err)
+	}
+
+	if config.Bootstrap {
+		configuration := raft.Configuration{
+			Servers: []raft.Server{
+				{ID: raftConfig.LocalID, Address: transport.LocalAddr()},
+			},
+		}
+		if err := r.BootstrapCluster(configuration).Error(); err != nil {
+			return nil, fmt.Errorf("cluster: bootstrapping: %w", err)
+		}
+	}
+
+	c := &ClusteredSharedStateManager{
+		SharedStateManager: ssm,
+		raft:               r,
+		fsm:                f,
+		config:             config,
+	}
+	go c.watchLeadership(leaderCh)
+	return c, nil
+}
+
+// IsLeader reports whether this node currently holds Raft leadership.
+func (c *ClusteredSharedStateManager) IsLeader() bool {
+	return c.raft.State() == raft.Leader
+}
+
+// Leader returns the address of the current leader, if known.
+func (c *ClusteredSharedStateManager) Leader() string {
+	addr, _ := c.raft.LeaderWithID()
+	return string(addr)
+}
+
+// Join adds a voting node to the cluster. Must be called against the leader.
+func (c *ClusteredSharedStateManager) Join(nodeID, addr string) error {
+	if !c.IsLeader() {
+		return ErrNotLeader
+	}
+	future := c.raft.AddVoter(raft.ServerID(nodeID), raft.ServerAddress(addr), 0, 0)
+	return future.Error()
+}
+
+// Leave removes a node from the cluster. Must be called against the leader.
+func (c *ClusteredSharedStateManager) Leave(nodeID string) error {
+	if !c.IsLeader() {
+		return ErrNotLeader
+	}
+	future := c.raft.RemoveServer(raft.ServerID(nodeID), 0, 0)
+	return future.Error()
+}
+
+// Set replicates a key/value write through the Raft log before it is applied
+// to local state on every node (including this one).
+func (c *ClusteredSharedStateManager) Set(key string, value interface{}) error {
+	return c.apply(command{Op: opSet, Key: key, Value: value})
+}
+
+// Delete replicates a key deletion through the Raft log.
+func (c *ClusteredSharedStateManager) Delete(key string) error {
+	return c.apply(command{Op: opDelete, Key: key})
+}
+
+// SetWithTimeout replicates a key/value write with an expiration. The timer
+// itself only ever runs on the leader: when it fires, the leader proposes an
+// explicit opExpire log entry so every node expires the key at the same
+// logical point in the log rather than drifting on independent wall clocks.
+// ExpireAt is replicated along with the value so that if this leader steps
+// down or crashes before the timer fires, whichever node takes over next can
+// re-arm it from PendingExpirations (see watchLeadership) instead of the
+// expiration being lost.
+func (c *ClusteredSharedStateManager) SetWithTimeout(key string, value interface{}, duration time.Duration) error {
+	expireAt := time.Now().Add(duration)
+	if err := c.apply(command{Op: opSet, Key: key, Value: value, ExpireAt: &expireAt}); err != nil {
+		return err
+	}
+	if c.IsLeader() {
+		c.armExpiry(key, duration)
+	}
+	return nil
+}
+
+// armExpiry schedules key to be proposed for expiration after duration,
+// re-checking leadership when the timer fires since it may have changed in
+// the meantime.
+func (c *ClusteredSharedStateManager) armExpiry(key string, duration time.Duration) {
+	time.AfterFunc(duration, func() {
+		if c.IsLeader() {
+			_ = c.apply(command{Op: opExpire, Key: key})
+		}
+	})
+}
+
+// watchLeadership re-arms every pending SetWithTimeout expiration whenever
+// this node becomes leader. Timers only ever run on the leader, so the
+// previous leader's in-memory timers are simply gone once it steps down or
+// crashes — without this, any key whose timer hadn't fired yet would never
+// expire.
+func (c *ClusteredSharedStateManager) watchLeadership(notify <-chan bool) {
+	for isLeader := range notify {
+		if isLeader {
+			c.rearmExpirations()
+		}
+	}
+}
+
+// rearmExpirations re-derives each pending key's remaining TTL from
+// replicated state (PendingExpirations) and arms a timer for it, so a newly
+// promoted leader resumes proposing opExpire entries where the old leader
+// left off rather than losing them.
+func (c *ClusteredSharedStateManager) rearmExpirations() {
+	now := time.Now()
+	for key, expireAt := range c.PendingExpirations() {
+		remaining := expireAt.Sub(now)
+		if remaining < 0 {
+			remaining = 0
+		}
+		c.armExpiry(key, remaining)
+	}
+}
+
+func (c *ClusteredSharedStateManager) apply(cmd command) error {
+	if !c.IsLeader() {
+		return ErrNotLeader
+	}
+	payload, err := encodeCommand(cmd)
+	if err != nil {
+		return fmt.Errorf("cluster: encoding command: %w", err)
+	}
+	future := c.raft.Apply(payload, 10*time.Second)
+	return future.Error()
+}
+
+// Shutdown gracefully stops Raft participation for this node.
+func (c *ClusteredSharedStateManager) Shutdown() error {
+	return c.raft.Shutdown().Error()
+}
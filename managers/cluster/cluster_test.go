@@ -0,0 +1,162 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+)
+
+// waitFor polls cond until it reports true or timeout elapses, failing the
+// test otherwise. Raft's own timers (election, heartbeat, apply) are real
+// wall-clock timers even with an in-memory transport, so tests that exercise
+// leadership changes have to poll rather than assert synchronously.
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("condition not met within %s", timeout)
+}
+
+// newInmemNode starts a node wired to an in-memory Raft transport instead of
+// a real TCP listener, so cluster behavior can be tested without binding
+// real sockets. Callers that Join more than one node together must also
+// Connect their transports to each other (see TestClusterJoinReplicatesWrites).
+func newInmemNode(t *testing.T, nodeID string, bootstrap bool) (*ClusteredSharedStateManager, *raft.InmemTransport) {
+	t.Helper()
+	addr, transport := raft.NewInmemTransport(raft.ServerAddress(nodeID))
+	c, err := newClusteredSharedStateManager(ClusterConfig{
+		NodeID:    nodeID,
+		RaftDir:   t.TempDir(),
+		BindAddr:  string(addr),
+		Bootstrap: bootstrap,
+	}, transport)
+	if err != nil {
+		t.Fatalf("newClusteredSharedStateManager(%s): %v", nodeID, err)
+	}
+	t.Cleanup(func() { c.Shutdown() })
+	return c, transport
+}
+
+func TestClusterSingleNodeBootstrapSetGet(t *testing.T) {
+	c, _ := newInmemNode(t, "node1", true)
+	waitFor(t, 5*time.Second, c.IsLeader)
+
+	if err := c.Set("greeting", "hello"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	value, exists := c.Get("greeting")
+	if !exists || value != "hello" {
+		t.Fatalf("expected Get to return %q, got %v (exists=%v)", "hello", value, exists)
+	}
+}
+
+// TestClusterCommandRoundTripsNumericValueAsFloat64 asserts the documented
+// JSON type-loss limitation on command/fsmSnapshot: a value only ever
+// becomes durable state via fsm.Apply, which decodes the replicated command
+// through encoding/json, so this applies even on the leader that proposed
+// the write.
+func TestClusterCommandRoundTripsNumericValueAsFloat64(t *testing.T) {
+	c, _ := newInmemNode(t, "node1", true)
+	waitFor(t, 5*time.Second, c.IsLeader)
+
+	if err := c.Set("count", 42); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	value, exists := c.Get("count")
+	if !exists {
+		t.Fatalf("expected key %q to exist", "count")
+	}
+	if _, ok := value.(float64); !ok {
+		t.Fatalf("expected numeric value to come back as float64 (documented command limitation), got %T", value)
+	}
+}
+
+func TestClusterJoinReplicatesWrites(t *testing.T) {
+	leader, leaderTransport := newInmemNode(t, "node1", true)
+	waitFor(t, 5*time.Second, leader.IsLeader)
+
+	follower, followerTransport := newInmemNode(t, "node2", false)
+	leaderTransport.Connect(followerTransport.LocalAddr(), followerTransport)
+	followerTransport.Connect(leaderTransport.LocalAddr(), leaderTransport)
+
+	if err := leader.Join("node2", string(followerTransport.LocalAddr())); err != nil {
+		t.Fatalf("Join: %v", err)
+	}
+
+	if err := leader.Set("key", "value"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	waitFor(t, 5*time.Second, func() bool {
+		value, exists := follower.Get("key")
+		return exists && value == "value"
+	})
+}
+
+// TestClusterLeadershipFailoverRearmsExpiration guards against the class of
+// bug that motivated PendingExpirations/rearmExpirations: the old leader's
+// in-process timer for a SetWithTimeout key disappears with it, so unless
+// the newly promoted leader re-arms that expiration from replicated state,
+// the key would never expire.
+func TestClusterLeadershipFailoverRearmsExpiration(t *testing.T) {
+	leader, leaderTransport := newInmemNode(t, "node1", true)
+	waitFor(t, 5*time.Second, leader.IsLeader)
+
+	// A two-node cluster can't survive losing either node (quorum needs both),
+	// so this needs a third node: once the leader is gone, the remaining two
+	// still have the majority they need to elect a new one.
+	follower1, follower1Transport := newInmemNode(t, "node2", false)
+	follower2, follower2Transport := newInmemNode(t, "node3", false)
+	for _, pair := range [][2]*raft.InmemTransport{
+		{leaderTransport, follower1Transport},
+		{leaderTransport, follower2Transport},
+		{follower1Transport, follower2Transport},
+	} {
+		pair[0].Connect(pair[1].LocalAddr(), pair[1])
+		pair[1].Connect(pair[0].LocalAddr(), pair[0])
+	}
+
+	if err := leader.Join("node2", string(follower1Transport.LocalAddr())); err != nil {
+		t.Fatalf("Join node2: %v", err)
+	}
+	if err := leader.Join("node3", string(follower2Transport.LocalAddr())); err != nil {
+		t.Fatalf("Join node3: %v", err)
+	}
+
+	if err := leader.SetWithTimeout("session", "active", 300*time.Millisecond); err != nil {
+		t.Fatalf("SetWithTimeout: %v", err)
+	}
+	waitFor(t, 5*time.Second, func() bool {
+		_, exists := follower1.Get("session")
+		return exists
+	})
+
+	// Remove node1 from the voter configuration before taking it down, so the
+	// remaining two don't waste the election waiting on RPCs to a peer they
+	// still believe is a voter.
+	if err := leader.Leave("node1"); err != nil {
+		t.Fatalf("Leave: %v", err)
+	}
+	if err := leader.Shutdown(); err != nil {
+		t.Fatalf("Shutdown leader: %v", err)
+	}
+
+	newLeader := follower1
+	waitFor(t, 10*time.Second, func() bool {
+		return follower1.IsLeader() || follower2.IsLeader()
+	})
+	if follower2.IsLeader() {
+		newLeader = follower2
+	}
+
+	waitFor(t, 5*time.Second, func() bool {
+		_, exists := newLeader.Get("session")
+		return !exists
+	})
+}
@@ -0,0 +1,52 @@
+package cluster
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type op string
+
+const (
+	opSet    op = "set"
+	opDelete op = "delete"
+	opExpire op = "expire"
+)
+
+// command is the unit replicated through the Raft log. Keeping it a small,
+// JSON-encodable struct makes log entries easy to inspect and keeps the wire
+// format stable across Go versions, unlike gob.
+//
+// ExpireAt is set on an opSet command that originated from SetWithTimeout, so
+// every node — not just the leader that issued it — learns the key's
+// deadline. Only the leader ever arms a wall-clock timer for it, but any node
+// can derive how much of that deadline remains from ExpireAt, which is what
+// lets a newly promoted leader resume expiring the key if the old leader
+// stepped down or crashed first.
+//
+// Because Value round-trips through encoding/json on every node — fsm.Apply
+// decodes the log entry before calling ApplyReplicatedSet, and that's the
+// only place state is actually mutated, even on the leader that proposed the
+// command — a recovered value's concrete Go type is whatever
+// json.Unmarshal produces into an interface{}, not necessarily the type it
+// was Set with. Notably, any numeric value comes back as float64, so e.g.
+// Set("n", 42) yields Get("n") == float64(42) on every node, leader
+// included, and managers/typed's Get[int] on the same key will report
+// not-found. This is the same round-trip behavior documented on FileStore;
+// see there for callers that need exact type preservation.
+type command struct {
+	Op       op          `json:"op"`
+	Key      string      `json:"key"`
+	Value    interface{} `json:"value,omitempty"`
+	ExpireAt *time.Time  `json:"expire_at,omitempty"`
+}
+
+func encodeCommand(cmd command) ([]byte, error) {
+	return json.Marshal(cmd)
+}
+
+func decodeCommand(data []byte) (command, error) {
+	var cmd command
+	err := json.Unmarshal(data, &cmd)
+	return cmd, err
+}
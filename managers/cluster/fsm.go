@@ -0,0 +1,62 @@
+package cluster
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/raft"
+
+	"github.com/christerso/shared-state-manager"
+)
+
+// fsm applies committed Raft log entries to the wrapped SharedStateManager.
+// Because raft.Raft calls Apply sequentially in log order on every node
+// (leader included), this is the only place state is actually mutated; the
+// client-facing Set/Delete/SetWithTimeout on ClusteredSharedStateManager only
+// ever propose commands.
+type fsm struct {
+	ssm *managers.SharedStateManager
+}
+
+func (f *fsm) Apply(log *raft.Log) interface{} {
+	cmd, err := decodeCommand(log.Data)
+	if err != nil {
+		return fmt.Errorf("cluster: decoding log entry %d: %w", log.Index, err)
+	}
+
+	switch cmd.Op {
+	case opSet:
+		if cmd.ExpireAt != nil {
+			f.ssm.ApplyReplicatedSetWithExpiry(cmd.Key, cmd.Value, *cmd.ExpireAt)
+		} else {
+			f.ssm.ApplyReplicatedSet(cmd.Key, cmd.Value)
+		}
+	case opDelete:
+		f.ssm.ApplyReplicatedDelete(cmd.Key)
+	case opExpire:
+		f.ssm.ApplyReplicatedExpire(cmd.Key)
+	default:
+		return fmt.Errorf("cluster: unknown op %q in log entry %d", cmd.Op, log.Index)
+	}
+	return nil
+}
+
+func (f *fsm) Snapshot() (raft.FSMSnapshot, error) {
+	return &fsmSnapshot{state: f.ssm.SnapshotWithExpiry()}, nil
+}
+
+func (f *fsm) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+	state, err := decodeSnapshot(rc)
+	if err != nil {
+		return fmt.Errorf("cluster: decoding snapshot: %w", err)
+	}
+	// Timers themselves are never part of a snapshot (see
+	// SharedStateManager.Restore), but each key's absolute expiration instant
+	// is (see SnapshotWithExpiry/RestoreWithExpiry), so a node that takes
+	// over leadership right after a snapshot restore can still re-arm
+	// expirations via PendingExpirations — see
+	// ClusteredSharedStateManager.rearmExpirations.
+	f.ssm.RestoreWithExpiry(state)
+	return nil
+}
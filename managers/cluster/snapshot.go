@@ -0,0 +1,45 @@
+package cluster
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/hashicorp/raft"
+
+	"github.com/christerso/shared-state-manager"
+)
+
+// fsmSnapshot is the raft.FSMSnapshot implementation used for log
+// compaction. It persists a point-in-time copy of stateMap paired with each
+// key's absolute expiration instant, if any; timers themselves are
+// re-derived from that instant rather than serialized (see fsm.Restore).
+//
+// Like command, this round-trips values through encoding/json, so a value
+// restored from a snapshot is subject to the same type loss documented on
+// command — e.g. a numeric value comes back as float64 regardless of what
+// it was Set with.
+type fsmSnapshot struct {
+	state map[string]managers.StoredValue
+}
+
+func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	err := func() error {
+		encoder := json.NewEncoder(sink)
+		return encoder.Encode(s.state)
+	}()
+	if err != nil {
+		sink.Cancel()
+		return err
+	}
+	return sink.Close()
+}
+
+func (s *fsmSnapshot) Release() {}
+
+func decodeSnapshot(r io.Reader) (map[string]managers.StoredValue, error) {
+	var state map[string]managers.StoredValue
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
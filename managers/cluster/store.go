@@ -0,0 +1,18 @@
+package cluster
+
+import (
+	"path/filepath"
+
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb"
+)
+
+// newStores opens the BoltDB-backed log store and stable store Raft needs
+// for its own log and vote/term bookkeeping, rooted at dir.
+func newStores(dir string) (raft.LogStore, raft.StableStore, error) {
+	boltStore, err := raftboltdb.NewBoltStore(filepath.Join(dir, "raft.db"))
+	if err != nil {
+		return nil, nil, err
+	}
+	return boltStore, boltStore, nil
+}
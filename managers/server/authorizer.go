@@ -0,0 +1,26 @@
+package server
+
+import "context"
+
+// Action is the kind of operation an Authorizer is asked to permit.
+type Action int
+
+const (
+	ActionRead Action = iota
+	ActionWrite
+	ActionSubscribe
+)
+
+// Authorizer grants or denies access to a key for a given Action.
+// Implementations typically key off per-key or per-prefix ACLs and caller
+// identity carried in ctx (e.g. from transport credentials or metadata).
+type Authorizer interface {
+	Authorize(ctx context.Context, key string, action Action) error
+}
+
+// AllowAll is an Authorizer that permits every request. It's the default
+// when a server is constructed without one.
+type AllowAll struct{}
+
+// Authorize always succeeds.
+func (AllowAll) Authorize(context.Context, string, Action) error { return nil }
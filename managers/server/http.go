@@ -0,0 +1,198 @@
+// Package server exposes a managers.SharedStateManager to remote processes
+// over a thin HTTP+SSE shim. proto/sharedstate.proto documents the intended
+// gRPC equivalent of this same service; a GRPCServer implementing it belongs
+// in this package once the generate directive below has produced its stubs
+// into managers/server/pb — committing a hand-written implementation against
+// a pb package that doesn't exist would leave the package permanently
+// broken, so it's deferred until the stubs are generated and checked in
+// alongside it.
+//
+// Status: still incomplete, not silently dropped. Tracked here rather than
+// in the proto file because this is where GRPCServer will live once it
+// exists.
+//
+//go:generate protoc --go_out=. --go_opt=module=github.com/christerso/shared-state-manager/managers/server --go-grpc_out=. --go-grpc_opt=module=github.com/christerso/shared-state-manager/managers/server proto/sharedstate.proto
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/christerso/shared-state-manager"
+)
+
+// HTTPServer exposes a SharedStateManager over plain HTTP, with a
+// server-sent-events endpoint for subscriptions.
+type HTTPServer struct {
+	SSM        *managers.SharedStateManager
+	Authorizer Authorizer
+	Codecs     *CodecRegistry
+}
+
+// NewHTTPServer returns an HTTPServer with AllowAll authorization and a
+// default (JSON-only) codec registry.
+func NewHTTPServer(ssm *managers.SharedStateManager) *HTTPServer {
+	return &HTTPServer{SSM: ssm, Authorizer: AllowAll{}, Codecs: NewCodecRegistry()}
+}
+
+func (h *HTTPServer) authorizer() Authorizer {
+	if h.Authorizer != nil {
+		return h.Authorizer
+	}
+	return AllowAll{}
+}
+
+func (h *HTTPServer) codecs() *CodecRegistry {
+	if h.Codecs != nil {
+		return h.Codecs
+	}
+	return NewCodecRegistry()
+}
+
+// Handler returns an http.Handler serving /v1/get, /v1/set,
+// /v1/set_with_timeout, /v1/delete, and /v1/subscribe (SSE).
+func (h *HTTPServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/get", h.handleGet)
+	mux.HandleFunc("/v1/set", h.handleSet)
+	mux.HandleFunc("/v1/set_with_timeout", h.handleSetWithTimeout)
+	mux.HandleFunc("/v1/delete", h.handleDelete)
+	mux.HandleFunc("/v1/subscribe", h.handleSubscribe)
+	return mux
+}
+
+type setRequest struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+type setWithTimeoutRequest struct {
+	Key        string          `json:"key"`
+	Value      json.RawMessage `json:"value"`
+	DurationMs int64           `json:"duration_ms"`
+}
+
+type deleteRequest struct {
+	Key string `json:"key"`
+}
+
+func (h *HTTPServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if err := h.authorizer().Authorize(r.Context(), key, ActionRead); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	value, exists := h.SSM.Get(key)
+	if !exists {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	data, err := h.codecs().For(key).Encode(value)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func (h *HTTPServer) handleSet(w http.ResponseWriter, r *http.Request) {
+	var req setRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.authorizer().Authorize(r.Context(), req.Key, ActionWrite); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	var value interface{}
+	if err := h.codecs().For(req.Key).Decode(req.Value, &value); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.SSM.Set(req.Key, value)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPServer) handleSetWithTimeout(w http.ResponseWriter, r *http.Request) {
+	var req setWithTimeoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.authorizer().Authorize(r.Context(), req.Key, ActionWrite); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	var value interface{}
+	if err := h.codecs().For(req.Key).Decode(req.Value, &value); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.SSM.SetWithTimeout(req.Key, value, time.Duration(req.DurationMs)*time.Millisecond)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *HTTPServer) handleDelete(w http.ResponseWriter, r *http.Request) {
+	var req deleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.authorizer().Authorize(r.Context(), req.Key, ActionWrite); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	h.SSM.Delete(req.Key)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleSubscribe streams value changes and expirations for a key as
+// server-sent events until the client disconnects, at which point the
+// subscription is torn down via Unsubscribe.
+func (h *HTTPServer) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	conditional := r.URL.Query().Get("conditional") == "true"
+	if err := h.authorizer().Authorize(r.Context(), key, ActionSubscribe); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := make(chan interface{})
+	id := h.SSM.SubscribeWithOptions(key, ch, conditional, managers.SubscribeOptions{})
+	defer h.SSM.Unsubscribe(id)
+
+	codec := h.codecs().For(key)
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case value, ok := <-ch:
+			if !ok {
+				return
+			}
+			if _, expired := value.(managers.ExpirationNotification); expired {
+				fmt.Fprintf(w, "event: expired\ndata: {\"key\":%q}\n\n", key)
+			} else {
+				data, err := codec.Encode(value)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: value\ndata: %s\n\n", data)
+			}
+			flusher.Flush()
+		}
+	}
+}
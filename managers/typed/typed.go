@@ -0,0 +1,143 @@
+// Package typed adds a generic, compile-time-typed companion API on top of
+// managers.SharedStateManager. The untyped API forces callers into
+// interface{} and ad-hoc type assertions, and its conditional notifications
+// compare values with != — which panics for uncomparable types such as
+// slices, maps, or structs containing them. TypedKey and Subscribe avoid
+// both problems while delegating all actual storage to the existing
+// SharedStateManager underneath.
+package typed
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/christerso/shared-state-manager"
+)
+
+// TypedKey associates a SharedStateManager key with a concrete Go type T, so
+// Get/Set/Subscribe can be type-safe instead of returning interface{}.
+type TypedKey[T any] struct {
+	Key string
+}
+
+// NewKey returns a TypedKey for key.
+func NewKey[T any](key string) TypedKey[T] {
+	return TypedKey[T]{Key: key}
+}
+
+// Set stores v under key.
+func Set[T any](ssm *managers.SharedStateManager, key TypedKey[T], v T) {
+	ssm.Set(key.Key, v)
+}
+
+// SetWithTimeout stores v under key with an expiration.
+func SetWithTimeout[T any](ssm *managers.SharedStateManager, key TypedKey[T], v T, duration time.Duration) {
+	ssm.SetWithTimeout(key.Key, v, duration)
+}
+
+// Get retrieves the value stored under key. It reports false both when the
+// key is absent and when the stored value isn't a T (e.g. it was written as
+// some other type through the untyped API).
+func Get[T any](ssm *managers.SharedStateManager, key TypedKey[T]) (T, bool) {
+	var zero T
+	raw, exists := ssm.Get(key.Key)
+	if !exists {
+		return zero, false
+	}
+	v, ok := raw.(T)
+	if !ok {
+		return zero, false
+	}
+	return v, true
+}
+
+// Delete removes key.
+func Delete[T any](ssm *managers.SharedStateManager, key TypedKey[T]) {
+	ssm.Delete(key.Key)
+}
+
+// EventKind discriminates the variants of Event.
+type EventKind int
+
+const (
+	// EventValue means Value holds the newly set value.
+	EventValue EventKind = iota
+	// EventExpired means the key was removed, either because its
+	// SetWithTimeout duration elapsed or because it was explicitly
+	// Deleted. The underlying SharedStateManager emits the same
+	// ExpirationNotification for both cases, so the typed API can't yet
+	// tell them apart; Value is the zero value of T for this kind.
+	EventExpired
+)
+
+// Event is delivered to a Subscribe handler.
+type Event[T any] struct {
+	Kind  EventKind
+	Value T
+}
+
+// SubscribeOptions configures a typed subscription.
+type SubscribeOptions[T any] struct {
+	// Conditional, when true, skips EventValue callbacks whose value equals
+	// the previously delivered one, as judged by Equal.
+	Conditional bool
+	// Equal compares two T for the purposes of Conditional. A nil Equal
+	// falls back to reflect.DeepEqual, which is safe for any T — including
+	// slices, maps, and structs containing them, unlike the untyped API's
+	// `!=` comparison.
+	Equal func(a, b T) bool
+	// Delivery configures the underlying subscription's ring buffer. See
+	// managers.SubscribeOptions.
+	Delivery managers.SubscribeOptions
+}
+
+// Subscribe registers handler to run for every value change and expiration
+// of key. It returns a stop function that the caller must call when done:
+// unlike the untyped API's Unsubscribe, which closes nothing because it
+// doesn't own the caller's channel, Subscribe owns its internal forwarding
+// channel and goroutine, and there is no other way to release them.
+func Subscribe[T any](ssm *managers.SharedStateManager, key TypedKey[T], opts SubscribeOptions[T], handler func(Event[T])) func() {
+	equal := opts.Equal
+	if equal == nil {
+		equal = func(a, b T) bool { return reflect.DeepEqual(a, b) }
+	}
+
+	ch := make(chan interface{})
+	// Conditional filtering happens below using equal, not through the
+	// untyped API's own conditional flag, since that flag's `!=` comparison
+	// is exactly the panic hazard this package exists to avoid.
+	id := ssm.SubscribeWithOptions(key.Key, ch, false, opts.Delivery)
+	done := make(chan struct{})
+
+	go func() {
+		var last T
+		var haveLast bool
+		for {
+			select {
+			case raw := <-ch:
+				if _, expired := raw.(managers.ExpirationNotification); expired {
+					haveLast = false
+					handler(Event[T]{Kind: EventExpired})
+					continue
+				}
+
+				value, ok := raw.(T)
+				if !ok {
+					continue
+				}
+				if opts.Conditional && haveLast && equal(last, value) {
+					continue
+				}
+				last, haveLast = value, true
+				handler(Event[T]{Kind: EventValue, Value: value})
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		ssm.Unsubscribe(id)
+		close(done)
+	}
+}
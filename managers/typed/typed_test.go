@@ -0,0 +1,34 @@
+package typed
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/christerso/shared-state-manager"
+)
+
+// TestSubscribeStopReleasesGoroutine guards against a regression where
+// Subscribe's internal forwarding channel was never closed and never had any
+// other way to unblock its delivery goroutine's `for range ch` loop, so every
+// Subscribe/Unsubscribe pair leaked a goroutine for the life of the process.
+func TestSubscribeStopReleasesGoroutine(t *testing.T) {
+	ssm := managers.NewSharedStateManager()
+	key := NewKey[int]("count")
+
+	before := runtime.NumGoroutine()
+
+	const n = 50
+	stops := make([]func(), n)
+	for i := 0; i < n; i++ {
+		stops[i] = Subscribe(ssm, key, SubscribeOptions[int]{}, func(Event[int]) {})
+	}
+	for _, stop := range stops {
+		stop()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if after := runtime.NumGoroutine(); after > before+2 {
+		t.Fatalf("goroutine count grew from %d to %d after %d Subscribe/stop pairs; Subscribe must not leak its forwarding goroutine", before, after, n)
+	}
+}
@@ -0,0 +1,115 @@
+package managers
+
+import (
+	"fmt"
+	"time"
+)
+
+// ExpiredOnRecoveryPolicy controls what NewSharedStateManagerWithStore does
+// with a recovered key whose TTL already elapsed while the process was down.
+type ExpiredOnRecoveryPolicy int
+
+const (
+	// DiscardExpiredOnRecovery drops already-expired keys silently; they
+	// never appear in the recovered state and no subscriber is notified.
+	DiscardExpiredOnRecovery ExpiredOnRecoveryPolicy = iota
+	// NotifyExpiredOnRecovery loads already-expired keys and then expires
+	// them again a short grace period later, so subscribers that register
+	// immediately after construction still observe the expiration.
+	NotifyExpiredOnRecovery
+)
+
+// recoveryExpirationGrace is how long NotifyExpiredOnRecovery waits before
+// re-expiring an already-elapsed key, giving the caller a window to Subscribe
+// after NewSharedStateManagerWithStore returns.
+const recoveryExpirationGrace = 50 * time.Millisecond
+
+// NewSharedStateManagerWithStore creates a manager backed by store for
+// durability. It replays store's most recent snapshot, then its WAL tail, to
+// reconstruct stateMap before returning, re-arming each recovered timed key's
+// timer from its remaining TTL (ExpireAt - now). All subsequent writes are
+// appended to store.
+//
+// With a FileStore, recovered values have passed through encoding/json and
+// so may come back as a different concrete Go type than they were Set with
+// (see FileStore's doc comment) — this affects any caller doing a type
+// assertion or using managers/typed against a key that survived a restart.
+func NewSharedStateManagerWithStore(store Store, policy ExpiredOnRecoveryPolicy) (*SharedStateManager, error) {
+	ssm := NewSharedStateManager()
+	ssm.store = store
+
+	state, ok, err := store.LoadSnapshot()
+	if err != nil {
+		return nil, fmt.Errorf("managers: loading snapshot: %w", err)
+	}
+	if !ok {
+		state = make(map[string]StoredValue)
+	}
+
+	tail, err := store.LoadTail()
+	if err != nil {
+		return nil, fmt.Errorf("managers: loading WAL tail: %w", err)
+	}
+	for _, rec := range tail {
+		switch rec.Op {
+		case RecordSet:
+			state[rec.Key] = StoredValue{Value: rec.Value, ExpireAt: rec.ExpireAt}
+		case RecordDelete, RecordExpire:
+			delete(state, rec.Key)
+		}
+	}
+
+	now := time.Now()
+	plain := make(map[string]interface{}, len(state))
+	var armTimers []struct {
+		key       string
+		remaining time.Duration
+	}
+	var expireSoon []string
+
+	for key, sv := range state {
+		switch {
+		case sv.ExpireAt == nil:
+			plain[key] = sv.Value
+		case sv.ExpireAt.After(now):
+			plain[key] = sv.Value
+			armTimers = append(armTimers, struct {
+				key       string
+				remaining time.Duration
+			}{key, sv.ExpireAt.Sub(now)})
+		case policy == NotifyExpiredOnRecovery:
+			plain[key] = sv.Value
+			expireSoon = append(expireSoon, key)
+		}
+	}
+
+	ssm.Restore(plain)
+	for _, t := range armTimers {
+		ssm.armRecoveredTimer(t.key, t.remaining)
+	}
+	for _, key := range expireSoon {
+		key := key
+		time.AfterFunc(recoveryExpirationGrace, func() { ssm.expireKey(key) })
+	}
+
+	if fileStore, ok := store.(*FileStore); ok && fileStore.config.SnapshotInterval > 0 {
+		ssm.stopCompactor = fileStore.StartCompactor(ssm.Compact, fileStore.config.SnapshotInterval)
+	}
+
+	return ssm, nil
+}
+
+// armRecoveredTimer schedules a key's expiration from its remaining TTL,
+// without touching the WAL: recovery is reconstructing existing state, not
+// recording a new write.
+func (ssm *SharedStateManager) armRecoveredTimer(key string, remaining time.Duration) {
+	ssm.mu.Lock()
+	defer ssm.mu.Unlock()
+	if existing, exists := ssm.timers[key]; exists {
+		existing.Stop()
+	}
+	ssm.expireAt[key] = time.Now().Add(remaining)
+	ssm.timers[key] = time.AfterFunc(remaining, func() {
+		ssm.expireKey(key)
+	})
+}
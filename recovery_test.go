@@ -0,0 +1,154 @@
+package managers
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileStoreRecoversStateAcrossRestart(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewFileStore(FileStoreConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	ssm, err := NewSharedStateManagerWithStore(store, DiscardExpiredOnRecovery)
+	if err != nil {
+		t.Fatalf("NewSharedStateManagerWithStore: %v", err)
+	}
+	ssm.Set("greeting", "hello")
+	ssm.Set("count", 42)
+	if err := ssm.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	store2, err := NewFileStore(FileStoreConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewFileStore (reopen): %v", err)
+	}
+	recovered, err := NewSharedStateManagerWithStore(store2, DiscardExpiredOnRecovery)
+	if err != nil {
+		t.Fatalf("NewSharedStateManagerWithStore (reopen): %v", err)
+	}
+	defer recovered.Close()
+
+	greeting, exists := recovered.GetString("greeting")
+	if !exists || greeting != "hello" {
+		t.Fatalf("expected recovered greeting %q, got %q (exists=%v)", "hello", greeting, exists)
+	}
+
+	// Numeric values round-trip through encoding/json as float64, not their
+	// original Go type; see FileStore's doc comment. This is asserting the
+	// documented limitation, not the desired behavior.
+	count, exists := recovered.Get("count")
+	if !exists {
+		t.Fatalf("expected key %q to survive recovery", "count")
+	}
+	if _, ok := count.(float64); !ok {
+		t.Fatalf("expected recovered numeric value to come back as float64 (documented FileStore limitation), got %T", count)
+	}
+}
+
+func TestFileStoreRecoveryRearmsTimerForFutureExpiry(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewFileStore(FileStoreConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	ssm, err := NewSharedStateManagerWithStore(store, DiscardExpiredOnRecovery)
+	if err != nil {
+		t.Fatalf("NewSharedStateManagerWithStore: %v", err)
+	}
+	ssm.SetWithTimeout("session", "active", 300*time.Millisecond)
+	if err := ssm.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	store2, err := NewFileStore(FileStoreConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewFileStore (reopen): %v", err)
+	}
+	recovered, err := NewSharedStateManagerWithStore(store2, DiscardExpiredOnRecovery)
+	if err != nil {
+		t.Fatalf("NewSharedStateManagerWithStore (reopen): %v", err)
+	}
+	defer recovered.Close()
+
+	if _, exists := recovered.Get("session"); !exists {
+		t.Fatalf("expected key to still be present immediately after recovery")
+	}
+
+	time.Sleep(500 * time.Millisecond)
+	if _, exists := recovered.Get("session"); exists {
+		t.Fatalf("expected recovered timer to have expired the key by now")
+	}
+}
+
+// TestCompactIsAtomicWithConcurrentWrites guards against the race where
+// Compact's snapshot capture and the store's WAL truncate happened under
+// separate lock acquisitions: a Set landing in the gap between them would
+// survive in neither the new snapshot nor the truncated WAL, so it would be
+// silently lost on the next recovery. Compact now holds ssm.mu for the whole
+// capture-and-save sequence, so every concurrent Set is either fully
+// reflected in the snapshot or still pending (and thus still in the WAL).
+func TestCompactIsAtomicWithConcurrentWrites(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := NewFileStore(FileStoreConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewFileStore: %v", err)
+	}
+	ssm, err := NewSharedStateManagerWithStore(store, DiscardExpiredOnRecovery)
+	if err != nil {
+		t.Fatalf("NewSharedStateManagerWithStore: %v", err)
+	}
+
+	const writers = 8
+	const setsPerWriter = 50
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		w := w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < setsPerWriter; i++ {
+				ssm.Set(fmt.Sprintf("key-%d-%d", w, i), i)
+			}
+		}()
+	}
+
+	for i := 0; i < 20; i++ {
+		if err := ssm.Compact(); err != nil {
+			t.Fatalf("Compact: %v", err)
+		}
+	}
+	wg.Wait()
+	if err := ssm.Compact(); err != nil {
+		t.Fatalf("final Compact: %v", err)
+	}
+	if err := ssm.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	store2, err := NewFileStore(FileStoreConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewFileStore (reopen): %v", err)
+	}
+	recovered, err := NewSharedStateManagerWithStore(store2, DiscardExpiredOnRecovery)
+	if err != nil {
+		t.Fatalf("NewSharedStateManagerWithStore (reopen): %v", err)
+	}
+	defer recovered.Close()
+
+	for w := 0; w < writers; w++ {
+		for i := 0; i < setsPerWriter; i++ {
+			key := fmt.Sprintf("key-%d-%d", w, i)
+			if _, exists := recovered.Get(key); !exists {
+				t.Fatalf("expected key %q to survive Compact racing with concurrent Set, but it's missing after recovery", key)
+			}
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package managers
+
+import (
+	"container/heap"
+	"time"
+)
+
+// ScheduleID identifies a schedule registered with Schedule, for later
+// cancellation.
+type ScheduleID uint64
+
+// ScheduleActionFunc is a user-supplied mutation run when a schedule fires.
+type ScheduleActionFunc func(*SharedStateManager)
+
+// ScheduleSpec describes when a schedule should fire and what it does when
+// it does. Exactly one of Once, Interval, or Weekday should be set to
+// describe the recurrence, and exactly one of SetKey, DeleteKey, or Func
+// should be set to describe the action:
+//
+//   - Once: fires a single time after the given delay, then is removed.
+//   - Interval: fires repeatedly, Interval apart, starting Interval from now.
+//   - Weekday: fires every week on Weekday at Hour:Minute:Second.
+type ScheduleSpec struct {
+	Once     time.Duration
+	Interval time.Duration
+	Weekday  *time.Weekday
+	Hour     int
+	Minute   int
+	Second   int
+
+	SetKey    string
+	SetValue  interface{}
+	DeleteKey string
+	Func      ScheduleActionFunc
+}
+
+// schedule is the internal bookkeeping for one registered ScheduleSpec.
+type schedule struct {
+	id       ScheduleID
+	spec     ScheduleSpec
+	nextFire time.Time
+	index    int // maintained by container/heap
+}
+
+// scheduleHeap orders schedules by nextFire so the scheduler goroutine only
+// ever needs to look at, and sleep until, the single soonest entry.
+type scheduleHeap []*schedule
+
+func (h scheduleHeap) Len() int           { return len(h) }
+func (h scheduleHeap) Less(i, j int) bool { return h[i].nextFire.Before(h[j].nextFire) }
+func (h scheduleHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *scheduleHeap) Push(x interface{}) {
+	s := x.(*schedule)
+	s.index = len(*h)
+	*h = append(*h, s)
+}
+
+func (h *scheduleHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	s := old[n-1]
+	old[n-1] = nil
+	s.index = -1
+	*h = old[:n-1]
+	return s
+}
+
+// Schedule registers spec with the manager and returns its ScheduleID. The
+// first call to Schedule on a manager lazily starts a single background
+// goroutine that services every schedule via a heap keyed on next-fire time,
+// so registering thousands of schedules stays cheap (no per-schedule
+// time.Timer).
+func (ssm *SharedStateManager) Schedule(spec ScheduleSpec) ScheduleID {
+	now := time.Now()
+	var nextFire time.Time
+	switch {
+	case spec.Weekday != nil:
+		nextFire = nextWeeklyFire(*spec.Weekday, spec.Hour, spec.Minute, spec.Second, now)
+	case spec.Interval > 0:
+		nextFire = now.Add(spec.Interval)
+	default:
+		nextFire = now.Add(spec.Once)
+	}
+
+	ssm.mu.Lock()
+	ssm.nextScheduleID++
+	id := ssm.nextScheduleID
+	s := &schedule{id: id, spec: spec, nextFire: nextFire}
+	ssm.schedules[id] = s
+	heap.Push(&ssm.scheduleHeap, s)
+	ssm.mu.Unlock()
+
+	ssm.schedulerOnce.Do(func() { go ssm.runScheduler() })
+	ssm.wakeScheduler()
+
+	return id
+}
+
+// Cancel removes a schedule before it fires again. It reports whether a
+// schedule with that ID was found.
+func (ssm *SharedStateManager) Cancel(id ScheduleID) bool {
+	ssm.mu.Lock()
+	s, exists := ssm.schedules[id]
+	if exists {
+		delete(ssm.schedules, id)
+		heap.Remove(&ssm.scheduleHeap, s.index)
+	}
+	ssm.mu.Unlock()
+
+	if exists {
+		ssm.wakeScheduler()
+	}
+	return exists
+}
+
+// List returns the IDs of all currently registered schedules, in no
+// particular order.
+func (ssm *SharedStateManager) List() []ScheduleID {
+	ssm.mu.RLock()
+	defer ssm.mu.RUnlock()
+	ids := make([]ScheduleID, 0, len(ssm.schedules))
+	for id := range ssm.schedules {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// wakeScheduler nudges the scheduler goroutine to recompute its sleep
+// duration, e.g. because a schedule earlier than its current wait was just
+// added or removed. It never blocks: a pending wake-up is as good as two.
+func (ssm *SharedStateManager) wakeScheduler() {
+	select {
+	case ssm.wakeSchedulerCh <- struct{}{}:
+	default:
+	}
+}
+
+// runScheduler is the single goroutine (lazily started by the first
+// Schedule call) that services every schedule on this manager. It sleeps
+// until the soonest schedule's nextFire, fires whatever is due, and repeats,
+// until Close closes schedulerDone.
+func (ssm *SharedStateManager) runScheduler() {
+	const idleWait = 24 * time.Hour
+	for {
+		ssm.mu.Lock()
+		wait := idleWait
+		if len(ssm.scheduleHeap) > 0 {
+			if w := time.Until(ssm.scheduleHeap[0].nextFire); w > 0 {
+				wait = w
+			} else {
+				wait = 0
+			}
+		}
+		ssm.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ssm.wakeSchedulerCh:
+			timer.Stop()
+		case <-ssm.schedulerDone:
+			timer.Stop()
+			return
+		}
+		ssm.fireDueSchedules()
+	}
+}
+
+// fireDueSchedules pops every schedule whose nextFire has passed, reschedules
+// recurring ones, and runs their actions outside the lock so a slow action
+// can't stall Set/Get/Delete on unrelated keys.
+func (ssm *SharedStateManager) fireDueSchedules() {
+	now := time.Now()
+
+	ssm.mu.Lock()
+	var due []*schedule
+	for len(ssm.scheduleHeap) > 0 && !ssm.scheduleHeap[0].nextFire.After(now) {
+		s := heap.Pop(&ssm.scheduleHeap).(*schedule)
+		due = append(due, s)
+		switch {
+		case s.spec.Interval > 0:
+			s.nextFire = now.Add(s.spec.Interval)
+			heap.Push(&ssm.scheduleHeap, s)
+		case s.spec.Weekday != nil:
+			s.nextFire = nextWeeklyFire(*s.spec.Weekday, s.spec.Hour, s.spec.Minute, s.spec.Second, now)
+			heap.Push(&ssm.scheduleHeap, s)
+		default:
+			delete(ssm.schedules, s.id)
+		}
+	}
+	ssm.mu.Unlock()
+
+	for _, s := range due {
+		ssm.runScheduleAction(s.spec)
+	}
+}
+
+func (ssm *SharedStateManager) runScheduleAction(spec ScheduleSpec) {
+	switch {
+	case spec.Func != nil:
+		spec.Func(ssm)
+	case spec.DeleteKey != "":
+		ssm.Delete(spec.DeleteKey)
+	default:
+		ssm.Set(spec.SetKey, spec.SetValue)
+	}
+}
+
+// nextWeeklyFire returns the first time strictly after `after` that falls on
+// weekday at hour:minute:second.
+func nextWeeklyFire(weekday time.Weekday, hour, minute, second int, after time.Time) time.Time {
+	candidate := time.Date(after.Year(), after.Month(), after.Day(), hour, minute, second, 0, after.Location())
+	for candidate.Weekday() != weekday || !candidate.After(after) {
+		candidate = candidate.AddDate(0, 0, 1)
+	}
+	return candidate
+}
+
+// IntervalBuilder is returned by Every and finalized by a unit method
+// (Seconds, Minutes, Hours) into a PendingSchedule.
+type IntervalBuilder struct {
+	ssm *SharedStateManager
+	n   int
+}
+
+// Every begins a fluent fixed-interval schedule, e.g. ssm.Every(5).Minutes().Do(fn).
+func (ssm *SharedStateManager) Every(n int) *IntervalBuilder {
+	return &IntervalBuilder{ssm: ssm, n: n}
+}
+
+func (b *IntervalBuilder) Seconds() *PendingSchedule { return b.unit(time.Second) }
+func (b *IntervalBuilder) Minutes() *PendingSchedule { return b.unit(time.Minute) }
+func (b *IntervalBuilder) Hours() *PendingSchedule   { return b.unit(time.Hour) }
+
+func (b *IntervalBuilder) unit(u time.Duration) *PendingSchedule {
+	return &PendingSchedule{ssm: b.ssm, spec: ScheduleSpec{Interval: time.Duration(b.n) * u}}
+}
+
+// After begins a fluent one-shot schedule, e.g. ssm.After(10*time.Second).Do(fn).
+func (ssm *SharedStateManager) After(d time.Duration) *PendingSchedule {
+	return &PendingSchedule{ssm: ssm, spec: ScheduleSpec{Once: d}}
+}
+
+// WeekdayBuilder is returned by the EveryXxx weekday helpers and finalized by
+// At into a PendingSchedule.
+type WeekdayBuilder struct {
+	ssm *SharedStateManager
+	day time.Weekday
+}
+
+// EveryWeekday begins a fluent calendar-aligned weekly schedule.
+func (ssm *SharedStateManager) EveryWeekday(day time.Weekday) *WeekdayBuilder {
+	return &WeekdayBuilder{ssm: ssm, day: day}
+}
+
+func (ssm *SharedStateManager) EveryMonday() *WeekdayBuilder  { return ssm.EveryWeekday(time.Monday) }
+func (ssm *SharedStateManager) EveryTuesday() *WeekdayBuilder { return ssm.EveryWeekday(time.Tuesday) }
+func (ssm *SharedStateManager) EveryWednesday() *WeekdayBuilder {
+	return ssm.EveryWeekday(time.Wednesday)
+}
+func (ssm *SharedStateManager) EveryThursday() *WeekdayBuilder {
+	return ssm.EveryWeekday(time.Thursday)
+}
+func (ssm *SharedStateManager) EveryFriday() *WeekdayBuilder { return ssm.EveryWeekday(time.Friday) }
+func (ssm *SharedStateManager) EverySaturday() *WeekdayBuilder {
+	return ssm.EveryWeekday(time.Saturday)
+}
+func (ssm *SharedStateManager) EverySunday() *WeekdayBuilder { return ssm.EveryWeekday(time.Sunday) }
+
+// At finalizes the weekday into a PendingSchedule firing every week at
+// hour:minute:second.
+func (b *WeekdayBuilder) At(hour, minute, second int) *PendingSchedule {
+	day := b.day
+	return &PendingSchedule{ssm: b.ssm, spec: ScheduleSpec{Weekday: &day, Hour: hour, Minute: minute, Second: second}}
+}
+
+// PendingSchedule accumulates recurrence details from a builder; Do, DoSet,
+// or DoDelete finalizes it into an action and registers it via Schedule.
+type PendingSchedule struct {
+	ssm  *SharedStateManager
+	spec ScheduleSpec
+}
+
+// Do registers fn to run each time the schedule fires.
+func (p *PendingSchedule) Do(fn ScheduleActionFunc) ScheduleID {
+	p.spec.Func = fn
+	return p.ssm.Schedule(p.spec)
+}
+
+// DoSet registers a Set(key, value) to run each time the schedule fires.
+func (p *PendingSchedule) DoSet(key string, value interface{}) ScheduleID {
+	p.spec.SetKey = key
+	p.spec.SetValue = value
+	return p.ssm.Schedule(p.spec)
+}
+
+// DoDelete registers a Delete(key) to run each time the schedule fires.
+func (p *PendingSchedule) DoDelete(key string) ScheduleID {
+	p.spec.DeleteKey = key
+	return p.ssm.Schedule(p.spec)
+}
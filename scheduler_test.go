@@ -0,0 +1,88 @@
+package managers
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestScheduleOnceFiresAndRemovesItself covers the basic Once recurrence: it
+// fires a single time after its delay and is gone from List afterward.
+func TestScheduleOnceFiresAndRemovesItself(t *testing.T) {
+	ssm := NewSharedStateManager()
+	defer ssm.Close()
+
+	id := ssm.After(20*time.Millisecond).DoSet("greeting", "hello")
+
+	waitForCondition(t, time.Second, func() bool {
+		value, exists := ssm.Get("greeting")
+		return exists && value == "hello"
+	})
+
+	waitForCondition(t, time.Second, func() bool {
+		for _, scheduled := range ssm.List() {
+			if scheduled == id {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// TestScheduleIntervalFiresRepeatedly covers the Interval recurrence: it
+// keeps firing, and Cancel stops further firings.
+func TestScheduleIntervalFiresRepeatedly(t *testing.T) {
+	ssm := NewSharedStateManager()
+	defer ssm.Close()
+
+	count := 0
+	id := ssm.Schedule(ScheduleSpec{
+		Interval: 20 * time.Millisecond,
+		Func:     func(*SharedStateManager) { count++ },
+	})
+
+	waitForCondition(t, time.Second, func() bool { return count >= 3 })
+
+	if !ssm.Cancel(id) {
+		t.Fatalf("expected Cancel to find schedule %d", id)
+	}
+	after := count
+	time.Sleep(100 * time.Millisecond)
+	if count > after+1 {
+		t.Fatalf("expected Cancel to stop further firings, count went from %d to %d", after, count)
+	}
+}
+
+// TestScheduleStopsOnClose guards against a goroutine leak: runScheduler, once
+// lazily started by the first Schedule/Every/After call, used to loop forever
+// with no way to stop it, so every SharedStateManager that ever scheduled
+// anything leaked one goroutine for its whole process lifetime. Close must
+// stop it.
+func TestScheduleStopsOnClose(t *testing.T) {
+	ssm := NewSharedStateManager()
+	ssm.Every(1).Hours().DoSet("unused", "unused")
+
+	// Let the scheduler goroutine actually start before measuring.
+	time.Sleep(20 * time.Millisecond)
+	before := runtime.NumGoroutine()
+
+	if err := ssm.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	waitForCondition(t, time.Second, func() bool {
+		return runtime.NumGoroutine() < before
+	})
+}
+
+func waitForCondition(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("condition not met within %s", timeout)
+}
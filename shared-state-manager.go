@@ -27,18 +27,26 @@ type ExpirationNotification struct {
 	Key string
 }
 
-// Subscription holds the channel and conditional flag for each subscriber.
-type Subscription struct {
-	Channel                  chan interface{}
-	ConditionalNotifications bool
-}
-
 // SharedStateManager manages states with string keys and any type of value.
 type SharedStateManager struct {
 	stateMap    map[string]interface{}
 	timers      map[string]*time.Timer
-	subscribers map[string][]Subscription
+	subscribers map[string][]*subscription
 	mu          sync.RWMutex
+
+	subscriptionByID   map[SubscriptionID]*subscription
+	nextSubscriptionID SubscriptionID
+
+	store         Store
+	expireAt      map[string]time.Time
+	stopCompactor func()
+
+	schedules       map[ScheduleID]*schedule
+	scheduleHeap    scheduleHeap
+	nextScheduleID  ScheduleID
+	wakeSchedulerCh chan struct{}
+	schedulerOnce   sync.Once
+	schedulerDone   chan struct{}
 }
 
 // NewSharedStateManager creates a new instance of SharedStateManager.
@@ -46,25 +54,38 @@ func NewSharedStateManager() *SharedStateManager {
 	return &SharedStateManager{
 		stateMap:    make(map[string]interface{}),
 		timers:      make(map[string]*time.Timer),
-		subscribers: make(map[string][]Subscription),
+		subscribers: make(map[string][]*subscription),
+
+		subscriptionByID: make(map[SubscriptionID]*subscription),
+
+		store:    NewInMemoryStore(),
+		expireAt: make(map[string]time.Time),
+
+		schedules:       make(map[ScheduleID]*schedule),
+		wakeSchedulerCh: make(chan struct{}, 1),
+		schedulerDone:   make(chan struct{}),
 	}
 }
 
 // Set sets a value for a given key.
 func (ssm *SharedStateManager) Set(key string, value interface{}) {
 	ssm.mu.Lock()
-	defer ssm.mu.Unlock()
 	oldValue, exists := ssm.stateMap[key]
 	ssm.stateMap[key] = value
-	ssm.notifySubscribers(key, value, exists, oldValue)
+	ssm.store.Append(Record{Op: RecordSet, Key: key, Value: value})
+	pending := ssm.notifySubscribers(key, value, exists, oldValue)
+	ssm.mu.Unlock()
+	flushPendingBlocks(pending)
 }
 
 // SetWithTimeout sets a value for a given key with an expiration time.
 func (ssm *SharedStateManager) SetWithTimeout(key string, value interface{}, duration time.Duration) {
 	ssm.mu.Lock()
-	defer ssm.mu.Unlock()
 	ssm.stateMap[key] = value
-	ssm.notifySubscribers(key, value, false, nil)
+	expireAt := time.Now().Add(duration)
+	ssm.expireAt[key] = expireAt
+	ssm.store.Append(Record{Op: RecordSet, Key: key, Value: value, ExpireAt: &expireAt})
+	pending := ssm.notifySubscribers(key, value, false, nil)
 
 	// Cancel any existing timer for the key
 	if timer, exists := ssm.timers[key]; exists {
@@ -75,30 +96,74 @@ func (ssm *SharedStateManager) SetWithTimeout(key string, value interface{}, dur
 		ssm.expireKey(key)
 	})
 	ssm.timers[key] = timer
+	ssm.mu.Unlock()
+	flushPendingBlocks(pending)
 }
 
-// notifySubscribers notifies all subscribers of a key's value change.
-func (ssm *SharedStateManager) notifySubscribers(key string, value interface{}, exists bool, oldValue interface{}) {
+// pendingBlock is a Block-policy enqueue that tryEnqueue couldn't complete
+// inline because the subscription's buffer was already full. See
+// notifySubscribers and flushPendingBlocks.
+type pendingBlock struct {
+	sub   *subscription
+	value interface{}
+}
+
+// notifySubscribers notifies all subscribers of a key's value change. It
+// only ever attempts a non-blocking tryEnqueue onto each subscription's own
+// ring buffer, so it never blocks itself — but a Block-policy subscription
+// whose buffer is already full can't be satisfied without waiting, and that
+// wait must happen after ssm.mu is released (a slow Block subscriber on one
+// key must not stall every other key's writers). notifySubscribers therefore
+// returns those as pendingBlock entries instead of waiting on them itself;
+// callers must release ssm.mu and then pass the result to
+// flushPendingBlocks.
+func (ssm *SharedStateManager) notifySubscribers(key string, value interface{}, exists bool, oldValue interface{}) []pendingBlock {
+	var pending []pendingBlock
 	for _, sub := range ssm.subscribers[key] {
-		if !sub.ConditionalNotifications || !exists || oldValue != value {
-			sub.Channel <- value
+		if !sub.conditional || !exists || oldValue != value {
+			if !sub.tryEnqueue(value) {
+				pending = append(pending, pendingBlock{sub: sub, value: value})
+			}
 		}
 	}
+	return pending
+}
+
+// notifyExpiration is notifySubscribers' counterpart for
+// ExpirationNotification delivery, which always goes to every subscriber on
+// the key regardless of the conditional flag. See notifySubscribers for why
+// it returns pendingBlock entries instead of waiting on them itself.
+func notifyExpiration(subs []*subscription, notification ExpirationNotification) []pendingBlock {
+	var pending []pendingBlock
+	for _, sub := range subs {
+		if !sub.tryEnqueue(notification) {
+			pending = append(pending, pendingBlock{sub: sub, value: notification})
+		}
+	}
+	return pending
+}
+
+// flushPendingBlocks finishes every pending Block enqueue, one at a time, on
+// the caller's own goroutine. Call this only after releasing ssm.mu.
+func flushPendingBlocks(pending []pendingBlock) {
+	for _, p := range pending {
+		p.sub.waitEnqueue(p.value)
+	}
 }
 
 // expireKey handles the expiration of a key.
 func (ssm *SharedStateManager) expireKey(key string) {
 	ssm.mu.Lock()
-	defer ssm.mu.Unlock()
 	delete(ssm.stateMap, key)
 	if timer, exists := ssm.timers[key]; exists {
 		timer.Stop()
 		delete(ssm.timers, key)
 	}
-	expirationNotification := ExpirationNotification{Key: key}
-	for _, sub := range ssm.subscribers[key] {
-		sub.Channel <- expirationNotification
-	}
+	delete(ssm.expireAt, key)
+	ssm.store.Append(Record{Op: RecordExpire, Key: key})
+	pending := notifyExpiration(ssm.subscribers[key], ExpirationNotification{Key: key})
+	ssm.mu.Unlock()
+	flushPendingBlocks(pending)
 }
 
 // Get retrieves a value for a given key.
@@ -135,26 +200,78 @@ func (ssm *SharedStateManager) GetStruct(key string) (interface{}, bool) {
 // Delete removes a key-value pair.
 func (ssm *SharedStateManager) Delete(key string) {
 	ssm.mu.Lock()
-	defer ssm.mu.Unlock()
 	delete(ssm.stateMap, key)
 	if timer, exists := ssm.timers[key]; exists {
 		timer.Stop()
 		delete(ssm.timers, key)
 	}
-	expirationNotification := ExpirationNotification{Key: key}
-	for _, sub := range ssm.subscribers[key] {
-		sub.Channel <- expirationNotification
-	}
+	delete(ssm.expireAt, key)
+	ssm.store.Append(Record{Op: RecordDelete, Key: key})
+	pending := notifyExpiration(ssm.subscribers[key], ExpirationNotification{Key: key})
+	ssm.mu.Unlock()
+	flushPendingBlocks(pending)
 }
 
-// Subscribe adds a subscriber for a specific key.
-func (ssm *SharedStateManager) Subscribe(key string, ch chan interface{}, conditional bool) {
+// Subscribe adds a subscriber for a specific key, using a default 16-entry
+// ring buffer that drops the oldest value on overflow. It returns a
+// SubscriptionID that can later be passed to Unsubscribe. For control over
+// buffering, use SubscribeWithOptions.
+func (ssm *SharedStateManager) Subscribe(key string, ch chan interface{}, conditional bool) SubscriptionID {
+	return ssm.SubscribeWithOptions(key, ch, conditional, SubscribeOptions{})
+}
+
+// SubscribeWithOptions adds a subscriber for a specific key with explicit
+// delivery options. Each subscription gets its own bounded ring buffer and a
+// dedicated goroutine that drains it into ch, so a slow consumer on one
+// subscription never stalls other subscribers. It stalls Set/Delete only
+// under the Block overflow policy, where the writing call waits for buffer
+// space after releasing the manager's lock, so writers on other keys are
+// unaffected.
+func (ssm *SharedStateManager) SubscribeWithOptions(key string, ch chan interface{}, conditional bool, opts SubscribeOptions) SubscriptionID {
 	ssm.mu.Lock()
 	defer ssm.mu.Unlock()
-	ssm.subscribers[key] = append(ssm.subscribers[key], Subscription{
-		Channel:                  ch,
-		ConditionalNotifications: conditional,
-	})
+	ssm.nextSubscriptionID++
+	id := ssm.nextSubscriptionID
+	sub := newSubscription(id, key, ch, conditional, opts)
+	ssm.subscribers[key] = append(ssm.subscribers[key], sub)
+	ssm.subscriptionByID[id] = sub
+	return id
+}
+
+// Unsubscribe stops and drains a subscription's delivery goroutine, closing
+// it cleanly. It reports whether a subscription with that ID was found.
+func (ssm *SharedStateManager) Unsubscribe(id SubscriptionID) bool {
+	ssm.mu.Lock()
+	sub, exists := ssm.subscriptionByID[id]
+	if exists {
+		delete(ssm.subscriptionByID, id)
+		subs := ssm.subscribers[sub.key]
+		for i, s := range subs {
+			if s.id == id {
+				ssm.subscribers[sub.key] = append(subs[:i], subs[i+1:]...)
+				break
+			}
+		}
+	}
+	ssm.mu.Unlock()
+
+	if exists {
+		sub.stop()
+	}
+	return exists
+}
+
+// SubscriptionMetrics reports the current queue depth and cumulative dropped
+// count for a subscription, or false if the ID is unknown (e.g. already
+// unsubscribed).
+func (ssm *SharedStateManager) SubscriptionMetrics(id SubscriptionID) (SubscriptionMetrics, bool) {
+	ssm.mu.RLock()
+	sub, exists := ssm.subscriptionByID[id]
+	ssm.mu.RUnlock()
+	if !exists {
+		return SubscriptionMetrics{}, false
+	}
+	return sub.metrics(), true
 }
 
 // StartSubscription starts a subscription goroutine with a handler.
@@ -167,3 +284,180 @@ func StartSubscription(ssm *SharedStateManager, key string, handler func(interfa
 		}
 	}()
 }
+
+// Snapshot returns a shallow copy of the current key-value state, suitable for
+// persisting or replicating elsewhere (e.g. a Raft log compaction snapshot).
+// Timers are intentionally not part of the snapshot; callers that need to
+// re-derive expiration should track remaining TTL out of band and call
+// SetWithTimeout again after Restore.
+func (ssm *SharedStateManager) Snapshot() map[string]interface{} {
+	ssm.mu.RLock()
+	defer ssm.mu.RUnlock()
+	snapshot := make(map[string]interface{}, len(ssm.stateMap))
+	for key, value := range ssm.stateMap {
+		snapshot[key] = value
+	}
+	return snapshot
+}
+
+// Restore replaces the current key-value state wholesale, stopping any
+// in-flight timers first. It does not notify subscribers, since callers
+// typically invoke Restore before subscribers have had a chance to register
+// (e.g. on startup from a snapshot).
+func (ssm *SharedStateManager) Restore(state map[string]interface{}) {
+	ssm.mu.Lock()
+	defer ssm.mu.Unlock()
+	for _, timer := range ssm.timers {
+		timer.Stop()
+	}
+	ssm.timers = make(map[string]*time.Timer)
+	ssm.expireAt = make(map[string]time.Time)
+	ssm.stateMap = make(map[string]interface{}, len(state))
+	for key, value := range state {
+		ssm.stateMap[key] = value
+	}
+}
+
+// snapshotForStore builds the durable representation of the current state,
+// pairing each value with its absolute expiration instant (if any) so a
+// Store can reconstruct remaining TTL after a restart.
+func (ssm *SharedStateManager) snapshotForStore() map[string]StoredValue {
+	ssm.mu.RLock()
+	defer ssm.mu.RUnlock()
+	return ssm.snapshotForStoreLocked()
+}
+
+// snapshotForStoreLocked is snapshotForStore without the locking, for
+// callers that already hold ssm.mu — see Compact, which needs the capture
+// to happen under the same lock acquisition as the store's truncate.
+func (ssm *SharedStateManager) snapshotForStoreLocked() map[string]StoredValue {
+	state := make(map[string]StoredValue, len(ssm.stateMap))
+	for key, value := range ssm.stateMap {
+		sv := StoredValue{Value: value}
+		if at, exists := ssm.expireAt[key]; exists {
+			at := at
+			sv.ExpireAt = &at
+		}
+		state[key] = sv
+	}
+	return state
+}
+
+// Compact asks the store to save a fresh snapshot of the full current state,
+// letting it discard whatever that snapshot makes redundant (e.g. a
+// FileStore truncating its WAL). Stores that need no compaction, such as
+// InMemoryStore, treat this as a no-op.
+//
+// Holds ssm.mu for the entire capture-and-save sequence, not just the
+// capture: Set/Delete also need ssm.mu, so this keeps one from landing in
+// the gap between the snapshot being captured and the store truncating
+// whatever made it redundant (e.g. FileStore's WAL) — a write in that gap
+// would otherwise end up in neither the new snapshot nor the post-truncate
+// WAL, silently lost on the next recovery.
+func (ssm *SharedStateManager) Compact() error {
+	ssm.mu.Lock()
+	defer ssm.mu.Unlock()
+	return ssm.store.SaveSnapshot(ssm.snapshotForStoreLocked())
+}
+
+// Close stops any background compaction started by
+// NewSharedStateManagerWithStore, stops the scheduler goroutine if Schedule,
+// Every, or After ever started one, and closes the underlying store.
+func (ssm *SharedStateManager) Close() error {
+	if ssm.stopCompactor != nil {
+		ssm.stopCompactor()
+	}
+	close(ssm.schedulerDone)
+	return ssm.store.Close()
+}
+
+// ApplyReplicatedSet mutates the state map and notifies subscribers exactly
+// like Set, but without the caller providing a new value through the normal
+// write path. It exists for replication layers (such as managers/cluster)
+// whose FSM applies already-ordered log entries directly to local state.
+func (ssm *SharedStateManager) ApplyReplicatedSet(key string, value interface{}) {
+	ssm.mu.Lock()
+	oldValue, exists := ssm.stateMap[key]
+	ssm.stateMap[key] = value
+	ssm.store.Append(Record{Op: RecordSet, Key: key, Value: value})
+	pending := ssm.notifySubscribers(key, value, exists, oldValue)
+	ssm.mu.Unlock()
+	flushPendingBlocks(pending)
+}
+
+// ApplyReplicatedDelete removes a key and notifies subscribers exactly like
+// Delete. See ApplyReplicatedSet for why this exists alongside Delete.
+func (ssm *SharedStateManager) ApplyReplicatedDelete(key string) {
+	ssm.Delete(key)
+}
+
+// ApplyReplicatedExpire applies an explicit expiration log entry. Replication
+// layers use this instead of letting each node run its own wall-clock timer,
+// so every node expires a key at the same logical point in the log.
+func (ssm *SharedStateManager) ApplyReplicatedExpire(key string) {
+	ssm.expireKey(key)
+}
+
+// ApplyReplicatedSetWithExpiry mutates the state map like ApplyReplicatedSet,
+// but also records key's absolute expiration instant so PendingExpirations
+// can report it. Replication layers use this for a replicated
+// SetWithTimeout, since only the leader runs a wall-clock timer for the
+// key (see ApplyReplicatedExpire) — every other node still needs to know the
+// deadline exists, so that it can re-arm a timer for it if it becomes leader
+// before the original leader expires the key.
+func (ssm *SharedStateManager) ApplyReplicatedSetWithExpiry(key string, value interface{}, expireAt time.Time) {
+	ssm.mu.Lock()
+	oldValue, exists := ssm.stateMap[key]
+	ssm.stateMap[key] = value
+	ssm.expireAt[key] = expireAt
+	ssm.store.Append(Record{Op: RecordSet, Key: key, Value: value, ExpireAt: &expireAt})
+	pending := ssm.notifySubscribers(key, value, exists, oldValue)
+	ssm.mu.Unlock()
+	flushPendingBlocks(pending)
+}
+
+// PendingExpirations returns a snapshot of every key with a recorded
+// expiration instant that has not been applied yet, keyed by its absolute
+// expiration time; that instant may already be in the past. Replication
+// layers use this to re-derive and re-arm expirations after a leadership
+// change, since timers themselves are never part of Snapshot/Restore.
+func (ssm *SharedStateManager) PendingExpirations() map[string]time.Time {
+	ssm.mu.RLock()
+	defer ssm.mu.RUnlock()
+	out := make(map[string]time.Time, len(ssm.expireAt))
+	for key, at := range ssm.expireAt {
+		out[key] = at
+	}
+	return out
+}
+
+// SnapshotWithExpiry is like Snapshot, but pairs each value with its absolute
+// expiration instant (if any). Replication layers that snapshot state
+// through something other than a Store (e.g. Raft log compaction) need this
+// to preserve pending expirations across the snapshot/restore boundary —
+// see RestoreWithExpiry.
+func (ssm *SharedStateManager) SnapshotWithExpiry() map[string]StoredValue {
+	return ssm.snapshotForStore()
+}
+
+// RestoreWithExpiry replaces the current key-value state wholesale like
+// Restore, additionally re-seeding expireAt bookkeeping for each key's
+// absolute expiration instant (without arming a timer for it). It does not
+// notify subscribers, for the same reason Restore doesn't. See
+// SnapshotWithExpiry for the paired snapshot side.
+func (ssm *SharedStateManager) RestoreWithExpiry(state map[string]StoredValue) {
+	ssm.mu.Lock()
+	defer ssm.mu.Unlock()
+	for _, timer := range ssm.timers {
+		timer.Stop()
+	}
+	ssm.timers = make(map[string]*time.Timer)
+	ssm.expireAt = make(map[string]time.Time)
+	ssm.stateMap = make(map[string]interface{}, len(state))
+	for key, sv := range state {
+		ssm.stateMap[key] = sv.Value
+		if sv.ExpireAt != nil {
+			ssm.expireAt[key] = *sv.ExpireAt
+		}
+	}
+}
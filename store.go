@@ -0,0 +1,67 @@
+package managers
+
+import "time"
+
+// RecordOp identifies the kind of mutation a Record represents in a Store's
+// write-ahead log.
+type RecordOp string
+
+const (
+	RecordSet    RecordOp = "set"
+	RecordDelete RecordOp = "delete"
+	RecordExpire RecordOp = "expire"
+)
+
+// Record is a single write-ahead log entry. ExpireAt is set for RecordSet
+// entries that originated from SetWithTimeout, so a Store can reconstruct
+// remaining TTL on recovery without replaying timer state.
+type Record struct {
+	Op       RecordOp
+	Key      string
+	Value    interface{}
+	ExpireAt *time.Time
+}
+
+// StoredValue is a key's durable representation: its value plus, for timed
+// keys, the absolute instant it expires.
+type StoredValue struct {
+	Value    interface{}
+	ExpireAt *time.Time
+}
+
+// Store is the durability backend a SharedStateManager delegates to. Writes
+// are appended as they happen; SaveSnapshot and LoadSnapshot let a Store
+// compact its log instead of replaying it from the beginning forever.
+//
+// Append is intentionally synchronous but does not return an error: a Store
+// that can fail (such as FileStore) reports failures through its own
+// configured error handler rather than through the hot write path, so
+// Set/SetWithTimeout/Delete keep their existing signatures.
+type Store interface {
+	Append(rec Record)
+	LoadSnapshot() (state map[string]StoredValue, ok bool, err error)
+	LoadTail() ([]Record, error)
+	SaveSnapshot(state map[string]StoredValue) error
+	Close() error
+}
+
+// InMemoryStore is the default Store: it persists nothing, matching
+// SharedStateManager's original in-memory-only behavior. It exists so
+// NewSharedStateManagerWithStore and the rest of the durability machinery
+// have a real Store to delegate to even when no durability is configured.
+type InMemoryStore struct{}
+
+// NewInMemoryStore returns a Store that discards everything it's given.
+func NewInMemoryStore() *InMemoryStore { return &InMemoryStore{} }
+
+func (*InMemoryStore) Append(Record) {}
+
+func (*InMemoryStore) LoadSnapshot() (map[string]StoredValue, bool, error) {
+	return nil, false, nil
+}
+
+func (*InMemoryStore) LoadTail() ([]Record, error) { return nil, nil }
+
+func (*InMemoryStore) SaveSnapshot(map[string]StoredValue) error { return nil }
+
+func (*InMemoryStore) Close() error { return nil }
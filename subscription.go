@@ -0,0 +1,196 @@
+package managers
+
+import "sync"
+
+// OverflowPolicy controls what a subscription does when its ring buffer is
+// full and a new value arrives before earlier ones have been delivered.
+type OverflowPolicy int
+
+const (
+	// DropOldest discards the oldest buffered value to make room for the
+	// incoming one. This is the default.
+	DropOldest OverflowPolicy = iota
+	// DropNewest discards the incoming value, leaving the buffer unchanged.
+	DropNewest
+	// Coalesce keeps only the latest value for the key, collapsing the
+	// buffer to a single slot. ExpirationNotification values are still
+	// always delivered, even if they arrive after values that were
+	// coalesced away.
+	Coalesce
+	// Block applies back-pressure instead of dropping anything: delivery
+	// waits for buffer space to free up.
+	Block
+)
+
+// SubscribeOptions configures delivery behavior for a single subscription.
+type SubscribeOptions struct {
+	// BufferSize is the capacity of the subscription's ring buffer. Zero or
+	// negative falls back to 16. Ignored (forced to 1) when OverflowPolicy
+	// is Coalesce.
+	BufferSize int
+	// OverflowPolicy decides what happens when the ring buffer is full.
+	OverflowPolicy OverflowPolicy
+}
+
+// SubscriptionID identifies a subscription for later Unsubscribe or
+// SubscriptionMetrics calls.
+type SubscriptionID uint64
+
+// SubscriptionMetrics reports point-in-time delivery health for one
+// subscription.
+type SubscriptionMetrics struct {
+	QueueDepth int
+	Dropped    uint64
+}
+
+// subscription owns a bounded ring buffer and a dedicated delivery goroutine,
+// decoupling notifySubscribers from however quickly the subscriber drains its
+// channel — except under the Block overflow policy, which deliberately
+// stalls the caller to provide real back-pressure. See tryEnqueue/waitEnqueue
+// for how that stall is kept from reaching SharedStateManager.mu.
+type subscription struct {
+	id          SubscriptionID
+	key         string
+	out         chan interface{}
+	conditional bool
+	opts        SubscribeOptions
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []interface{}
+	dropped uint64
+	closed  bool
+	done    chan struct{}
+}
+
+func newSubscription(id SubscriptionID, key string, out chan interface{}, conditional bool, opts SubscribeOptions) *subscription {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 16
+	}
+	if opts.OverflowPolicy == Coalesce {
+		opts.BufferSize = 1
+	}
+	sub := &subscription{
+		id:          id,
+		key:         key,
+		out:         out,
+		conditional: conditional,
+		opts:        opts,
+		done:        make(chan struct{}),
+	}
+	sub.cond = sync.NewCond(&sub.mu)
+	go sub.deliverLoop()
+	return sub
+}
+
+// tryEnqueue buffers value for delivery without ever blocking the caller.
+// For every policy except Block it always succeeds, applying the configured
+// drop/coalesce behavior on overflow. Under Block, it only succeeds if
+// there's already room; otherwise it reports false and does nothing,
+// leaving waitEnqueue to finish the job. An ExpirationNotification is the
+// exception under every policy: it is always buffered, even past
+// BufferSize, and tryEnqueue reports true for it. This split exists so that
+// SharedStateManager can call tryEnqueue while still holding ssm.mu (cheap,
+// never blocks) and defer any actual waiting until after ssm.mu is released
+// — see waitEnqueue.
+func (s *subscription) tryEnqueue(value interface{}) bool {
+	_, isExpiration := value.(ExpirationNotification)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if isExpiration || len(s.queue) < s.opts.BufferSize {
+		s.queue = append(s.queue, value)
+		s.cond.Signal()
+		return true
+	}
+
+	switch s.opts.OverflowPolicy {
+	case Block:
+		return false
+	case DropNewest:
+		s.dropped++
+	case Coalesce:
+		// Never overwrite a queued ExpirationNotification; the new value
+		// goes after it instead.
+		if _, lastIsExpiration := s.queue[len(s.queue)-1].(ExpirationNotification); lastIsExpiration {
+			s.queue = append(s.queue, value)
+		} else {
+			s.queue[len(s.queue)-1] = value
+			s.dropped++
+		}
+		s.cond.Signal()
+	default: // DropOldest
+		copy(s.queue, s.queue[1:])
+		s.queue[len(s.queue)-1] = value
+		s.dropped++
+		s.cond.Signal()
+	}
+	return true
+}
+
+// waitEnqueue finishes a Block enqueue that tryEnqueue couldn't complete
+// inline because the buffer was already full, waiting synchronously, on the
+// caller's own goroutine, for the deliverLoop to free up room — real
+// back-pressure, at the cost of stalling the caller until a slow subscriber
+// keeps up or the subscription is stopped. Callers must not hold
+// SharedStateManager.mu here: that would let one slow Block subscriber on
+// one key stall every other key's writers too, which defeats the entire
+// point of giving each subscription its own buffer. Spawning a goroutine per
+// call here instead would make Block both unbounded (one parked goroutine
+// per blocked write) and non-FIFO (the spawned goroutines would race each
+// other for s.mu), so it's deliberately not done.
+func (s *subscription) waitEnqueue(value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.queue) >= s.opts.BufferSize && !s.closed {
+		s.cond.Wait()
+	}
+	if s.closed {
+		return
+	}
+	s.queue = append(s.queue, value)
+	s.cond.Signal()
+}
+
+// deliverLoop drains the ring buffer into out, one value at a time, in
+// arrival order. It is the only goroutine that ever sends on out.
+func (s *subscription) deliverLoop() {
+	for {
+		s.mu.Lock()
+		for len(s.queue) == 0 && !s.closed {
+			s.cond.Wait()
+		}
+		if len(s.queue) == 0 && s.closed {
+			s.mu.Unlock()
+			return
+		}
+		value := s.queue[0]
+		s.queue = s.queue[1:]
+		s.cond.Signal() // wake any waitEnqueue waiting for space
+		s.mu.Unlock()
+
+		select {
+		case s.out <- value:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// stop closes the subscription, waking and terminating deliverLoop and any
+// pending waitEnqueue calls. It does not close out, since the caller
+// owns that channel.
+func (s *subscription) stop() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	close(s.done)
+	s.cond.Broadcast()
+}
+
+func (s *subscription) metrics() SubscriptionMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return SubscriptionMetrics{QueueDepth: len(s.queue), Dropped: s.dropped}
+}
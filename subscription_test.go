@@ -0,0 +1,143 @@
+package managers
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+// TestSubscriptionBlockDeliversInOrderWithoutLeakingGoroutines guards against
+// a regression where the Block overflow policy spawned a goroutine per
+// blocked enqueue instead of waiting on the caller: that both leaked a
+// goroutine per call and broke FIFO delivery, since the spawned goroutines
+// raced each other for the subscription's lock.
+func TestSubscriptionBlockDeliversInOrderWithoutLeakingGoroutines(t *testing.T) {
+	ssm := NewSharedStateManager()
+	ch := make(chan interface{})
+	ssm.SubscribeWithOptions("key", ch, false, SubscribeOptions{BufferSize: 4, OverflowPolicy: Block})
+
+	const n = 50
+	before := runtime.NumGoroutine()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < n; i++ {
+			ssm.Set("key", i)
+		}
+	}()
+
+	for i := 0; i < n; i++ {
+		select {
+		case v := <-ch:
+			if v.(int) != i {
+				t.Fatalf("expected %d, got %v (Block policy must deliver in FIFO order)", i, v)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for value %d", i)
+		}
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("writer goroutine never finished")
+	}
+
+	// Give any stray goroutines a moment to settle, then confirm the Block
+	// path didn't leave one goroutine parked per blocked Set call.
+	time.Sleep(50 * time.Millisecond)
+	if after := runtime.NumGoroutine(); after > before+2 {
+		t.Fatalf("goroutine count grew from %d to %d; Block must not spawn a goroutine per blocked enqueue", before, after)
+	}
+}
+
+// TestSubscriptionBlockDoesNotStallOtherKeys guards against a regression
+// where a Block-policy subscriber's wait happened while SharedStateManager
+// still held its lock, so a slow subscriber on one key stalled Set/Delete on
+// every other key too — defeating the entire point of giving each
+// subscription its own buffer.
+func TestSubscriptionBlockDoesNotStallOtherKeys(t *testing.T) {
+	ssm := NewSharedStateManager()
+	chA := make(chan interface{})
+	ssm.SubscribeWithOptions("a", chA, false, SubscribeOptions{BufferSize: 1, OverflowPolicy: Block})
+
+	// With a buffer size of 1, deliverLoop can have at most one value
+	// in-flight (popped from the queue, blocked trying to send on chA) plus
+	// one queued, so the first two Sets are guaranteed to complete without
+	// waiting; nothing is draining chA yet, so the third must block.
+	ssm.Set("a", 1)
+	ssm.Set("a", 2)
+
+	blockedSetDone := make(chan struct{})
+	go func() {
+		ssm.Set("a", 3) // both slots are full, so this must wait for chA to drain
+		close(blockedSetDone)
+	}()
+
+	// Give the goroutine above time to actually start waiting.
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case <-blockedSetDone:
+		t.Fatal("Set(\"a\", 3) returned before chA was drained; Block policy isn't applying back-pressure")
+	default:
+	}
+
+	otherKeyDone := make(chan struct{})
+	go func() {
+		ssm.Set("b", "unrelated")
+		close(otherKeyDone)
+	}()
+
+	select {
+	case <-otherKeyDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Set on an unrelated key must not stall while a Block subscriber on another key is full")
+	}
+
+	// Drain chA so the blocked goroutine can finish and not leak past the test.
+	if v := <-chA; v.(int) != 1 {
+		t.Fatalf("expected 1, got %v", v)
+	}
+	select {
+	case <-blockedSetDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("blocked Set never completed after chA was drained")
+	}
+}
+
+// TestSubscriptionDropOldestKeepsNewest covers the DropOldest overflow
+// policy, which must never block the caller and must keep the most recent
+// value once the buffer overflows.
+func TestSubscriptionDropOldestKeepsNewest(t *testing.T) {
+	ssm := NewSharedStateManager()
+	ch := make(chan interface{})
+	ssm.SubscribeWithOptions("key", ch, false, SubscribeOptions{BufferSize: 2, OverflowPolicy: DropOldest})
+
+	for i := 0; i < 5; i++ {
+		ssm.Set("key", i)
+	}
+
+	var got []int
+loop:
+	for {
+		select {
+		case v := <-ch:
+			got = append(got, v.(int))
+		case <-time.After(100 * time.Millisecond):
+			break loop
+		}
+	}
+
+	if len(got) == 0 || got[len(got)-1] != 4 {
+		t.Fatalf("expected the most recent value (4) to survive DropOldest, got %v", got)
+	}
+	if len(got) >= 5 {
+		t.Fatalf("expected DropOldest to drop something with a buffer of 2, got all values %v", got)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i] <= got[i-1] {
+			t.Fatalf("DropOldest delivery must stay in arrival order, got %v", got)
+		}
+	}
+}